@@ -0,0 +1,33 @@
+package odata
+
+import "github.com/FossoresLP/odata/apply"
+
+// ApplyAs re-parameterizes o to a new result type W and attaches p as its $apply pipeline. Use this instead
+// of Query[V].Apply when the pipeline aggregates or reshapes the result so it no longer matches V, e.g. a
+// groupby/aggregate pipeline whose rows have different fields than the underlying entity.
+// ApplyAs is a function rather than a method because Go does not allow methods to introduce new type parameters.
+func ApplyAs[V, W any](o *Query[V], p apply.Pipeline) *Query[W] {
+	orderBy := make(Order, len(o.orderBy))
+	for k, v := range o.orderBy {
+		orderBy[k] = v
+	}
+	pathParams := make(map[string]string, len(o.pathParams))
+	for k, v := range o.pathParams {
+		pathParams[k] = v
+	}
+	return &Query[W]{
+		client:     o.client,
+		url:        o.url,
+		count:      o.count,
+		expand:     append([]string(nil), o.expand...),
+		filter:     o.filter,
+		orderBy:    orderBy,
+		search:     o.search,
+		selectKeys: append([]string(nil), o.selectKeys...),
+		skip:       o.skip,
+		top:        o.top,
+		pathParams: pathParams,
+		etag:       o.etag,
+		apply:      p.String(),
+	}
+}
@@ -0,0 +1,115 @@
+// Package apply provides a composable builder for the OData 4.01 $apply system query option, used for
+// server-side aggregation such as groupby, aggregate, filter pipelines, compute and topcount.
+package apply
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/FossoresLP/odata/filter"
+)
+
+// Transform is a single $apply transformation that renders itself to valid OData 4.01 syntax
+type Transform interface {
+	String() string
+}
+
+// Pipeline joins a sequence of transformations with "/", applied left to right
+type Pipeline []Transform
+
+func (p Pipeline) String() string {
+	parts := make([]string, len(p))
+	for i, t := range p {
+		parts[i] = t.String()
+	}
+	return strings.Join(parts, "/")
+}
+
+type groupBy struct {
+	keys []string
+	with []Transform
+}
+
+func (g groupBy) String() string {
+	s := fmt.Sprintf("groupby((%s)", strings.Join(g.keys, ","))
+	if len(g.with) > 0 {
+		parts := make([]string, len(g.with))
+		for i, t := range g.with {
+			parts[i] = t.String()
+		}
+		s += "," + strings.Join(parts, ",")
+	}
+	return s + ")"
+}
+
+// GroupBy builds a "groupby((keys), inner)" transform, grouping by keys and applying the (optional) inner
+// transforms, typically an Aggregate, to each group
+func GroupBy(keys []string, inner ...Transform) Transform {
+	return groupBy{keys: keys, with: inner}
+}
+
+// AggregateMethod is one of the aggregation methods supported by OData's aggregate transformation
+type AggregateMethod string
+
+const (
+	Sum           AggregateMethod = "sum"
+	Min           AggregateMethod = "min"
+	Max           AggregateMethod = "max"
+	Average       AggregateMethod = "average"
+	CountDistinct AggregateMethod = "countdistinct"
+)
+
+type aggregate struct {
+	expr   string
+	method AggregateMethod
+	alias  string
+}
+
+func (a aggregate) String() string {
+	return fmt.Sprintf("aggregate(%s with %s as %s)", a.expr, a.method, a.alias)
+}
+
+// Aggregate builds an "aggregate(expr with method as alias)" transform
+func Aggregate(expr string, method AggregateMethod, alias string) Transform {
+	return aggregate{expr, method, alias}
+}
+
+type filterTransform struct {
+	expr filter.Expr
+}
+
+func (f filterTransform) String() string {
+	return "filter(" + f.expr.String() + ")"
+}
+
+// Filter builds a "filter(expr)" transform from a filter.Expr, as produced by the filter subpackage
+func Filter(expr filter.Expr) Transform {
+	return filterTransform{expr}
+}
+
+type compute struct {
+	expr, alias string
+}
+
+func (c compute) String() string {
+	return fmt.Sprintf("compute(%s as %s)", c.expr, c.alias)
+}
+
+// Compute builds a "compute(expr as alias)" transform, adding a computed property to each result
+func Compute(expr, alias string) Transform {
+	return compute{expr, alias}
+}
+
+type topCount struct {
+	n    uint64
+	expr string
+}
+
+func (t topCount) String() string {
+	return fmt.Sprintf("topcount(%d,%s)", t.n, t.expr)
+}
+
+// TopCount builds a "topcount(n,expr)" transform, keeping the top n results ordered by expr
+func TopCount(n uint64, expr string) Transform {
+	return topCount{n, expr}
+}
@@ -0,0 +1,482 @@
+package odata
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// BatchFormat selects the wire encoding used by Batch.Execute
+type BatchFormat uint8
+
+const (
+	// BatchMultipart encodes the batch as multipart/mixed per OData v4, RFC 2046
+	BatchMultipart BatchFormat = iota
+	// BatchJSON encodes the batch using the OData JSON batch format
+	BatchJSON
+)
+
+// batchPart is a single sub-request of a batch, either a standalone GET or a member of a change set
+type batchPart struct {
+	id      string
+	method  string
+	target  string
+	headers map[string]string
+	body    []byte
+
+	status     int
+	respHeader http.Header
+	respBody   []byte
+	responded  bool
+}
+
+func (p *batchPart) result() ([]byte, error) {
+	if !p.responded {
+		return nil, fmt.Errorf("odata: batch response did not contain a result for %q %s", p.method, p.target)
+	}
+	if p.status < 200 || p.status >= 300 {
+		return nil, newRequestErrorFromBody(p.status, http.StatusText(p.status), p.respBody)
+	}
+	return p.respBody, nil
+}
+
+// BatchRef is a handle to the result of a query added to a Batch via Add
+type BatchRef[V any] struct {
+	part *batchPart
+}
+
+// Result returns the decoded result of the GET this ref was created for, once the Batch has been executed
+func (r *BatchRef[V]) Result() (*V, error) {
+	body, err := r.part.result()
+	if err != nil {
+		return nil, err
+	}
+	if len(body) == 0 {
+		return nil, nil
+	}
+	result := new(V)
+	if err := json.Unmarshal(body, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ChangeOp is a single write operation (POST, PUT, PATCH or DELETE) to include in a change set.
+// URL may reference an earlier operation of the same change set using the "$<id>" notation, where
+// <id> is the 1-based position of that operation as returned by ChangeSetRef.ID.
+type ChangeOp struct {
+	Method  string
+	URL     string
+	Body    any
+	Headers map[string]string
+}
+
+// ChangeSetRef is a handle to the results of the operations added to a Batch via AddChangeSet.
+// All operations of a change set are applied atomically by the service.
+type ChangeSetRef struct {
+	parts []*batchPart
+}
+
+// ID returns the Content-ID assigned to the i-th operation of the change set, for use in a later
+// operation's ChangeOp.URL as "$<id>"
+func (c *ChangeSetRef) ID(i int) string {
+	return c.parts[i].id
+}
+
+// Result returns the raw response body of the i-th operation of the change set
+func (c *ChangeSetRef) Result(i int) ([]byte, error) {
+	return c.parts[i].result()
+}
+
+// requestTarget builds the relative URL (path plus query string) for q, the same way prepare() would
+// configure a resty.Request, for embedding as the request line of a batch part
+func (o *Query[V]) requestTarget() string {
+	target := o.url
+	for k, v := range o.pathParams {
+		target = strings.ReplaceAll(target, "{"+k+"}", url.PathEscape(v))
+	}
+	query := make(map[string][]string)
+	if o.count {
+		query["$count"] = []string{"true"}
+	}
+	if len(o.expand) > 0 {
+		query["$expand"] = []string{strings.Join(o.expand, ",")}
+	}
+	if len(o.filter) > 0 {
+		query["$filter"] = []string{o.filter}
+	}
+	if len(o.orderBy) > 0 {
+		query["$orderby"] = []string{o.orderBy.String()}
+	}
+	if len(o.search) > 0 {
+		query["$search"] = []string{o.search}
+	}
+	if len(o.selectKeys) > 0 {
+		query["$select"] = []string{strings.Join(o.selectKeys, ",")}
+	}
+	if len(o.apply) > 0 {
+		query["$apply"] = []string{o.apply}
+	}
+	if o.skip > 0 {
+		query["$skip"] = []string{strconv.FormatUint(o.skip, 10)}
+	}
+	if o.top > 0 {
+		query["$top"] = []string{strconv.FormatUint(o.top, 10)}
+	}
+	if len(query) == 0 {
+		return target
+	}
+	params := url.Values(query)
+	return target + "?" + params.Encode()
+}
+
+// Batch bundles multiple OData operations into a single $batch round-trip
+type Batch struct {
+	client     RequestProvider
+	url        string
+	format     BatchFormat
+	gets       []*batchPart
+	changeSets [][]*batchPart
+}
+
+// NewBatch creates a Batch that will POST to url (typically the service's "$batch" endpoint) using client.
+// An optional format selects multipart/mixed (the default) or the OData JSON batch encoding.
+func NewBatch(client RequestProvider, url string, format ...BatchFormat) *Batch {
+	f := BatchMultipart
+	if len(format) > 0 {
+		f = format[0]
+	}
+	return &Batch{client: client, url: url, format: f}
+}
+
+// Add queues a GET for q to be performed as part of the batch, returning a ref to retrieve its result after Execute.
+// Add is a function rather than a method because Go does not allow methods to introduce new type parameters.
+func Add[V any](b *Batch, q *Query[V]) *BatchRef[V] {
+	part := &batchPart{
+		id:      fmt.Sprintf("g%d", len(b.gets)),
+		method:  http.MethodGet,
+		target:  q.requestTarget(),
+		headers: map[string]string{},
+	}
+	b.gets = append(b.gets, part)
+	return &BatchRef[V]{part: part}
+}
+
+// AddChangeSet queues ops as a single atomic change set and returns a ref to retrieve their results after Execute
+func (b *Batch) AddChangeSet(ops ...ChangeOp) *ChangeSetRef {
+	parts := make([]*batchPart, len(ops))
+	for i, op := range ops {
+		headers := make(map[string]string, len(op.Headers)+1)
+		for k, v := range op.Headers {
+			headers[k] = v
+		}
+		var body []byte
+		if op.Body != nil {
+			body, _ = json.Marshal(op.Body)
+			headers["Content-Type"] = "application/json"
+		}
+		parts[i] = &batchPart{
+			id:      strconv.Itoa(i + 1),
+			method:  op.Method,
+			target:  op.URL,
+			headers: headers,
+			body:    body,
+		}
+	}
+	b.changeSets = append(b.changeSets, parts)
+	ref := &ChangeSetRef{parts: parts}
+	return ref
+}
+
+// Execute sends the batch and demultiplexes the response onto the BatchRef and ChangeSetRef handles returned by Add and AddChangeSet
+func (b *Batch) Execute(ctx context.Context) error {
+	req, err := newRequest(ctx, b.client)
+	if err != nil {
+		return err
+	}
+	if b.format == BatchJSON {
+		return b.executeJSON(req)
+	}
+	return b.executeMultipart(req)
+}
+
+func writeHTTPFragment(w io.Writer, p *batchPart) error {
+	if _, err := fmt.Fprintf(w, "%s %s HTTP/1.1\r\n", p.method, p.target); err != nil {
+		return err
+	}
+	for k, v := range p.headers {
+		if _, err := fmt.Fprintf(w, "%s: %s\r\n", k, v); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprint(w, "\r\n"); err != nil {
+		return err
+	}
+	if len(p.body) > 0 {
+		if _, err := w.Write(p.body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Batch) encodeMultipart() (string, []byte, error) {
+	buf := &bytes.Buffer{}
+	w := multipart.NewWriter(buf)
+	partHeader := textproto.MIMEHeader{
+		"Content-Type":              {"application/http"},
+		"Content-Transfer-Encoding": {"binary"},
+	}
+	for _, p := range b.gets {
+		part, err := w.CreatePart(partHeader)
+		if err != nil {
+			return "", nil, err
+		}
+		if err := writeHTTPFragment(part, p); err != nil {
+			return "", nil, err
+		}
+	}
+	for _, cs := range b.changeSets {
+		csBuf := &bytes.Buffer{}
+		csw := multipart.NewWriter(csBuf)
+		for _, p := range cs {
+			h := textproto.MIMEHeader{
+				"Content-Type":              {"application/http"},
+				"Content-Transfer-Encoding": {"binary"},
+				"Content-ID":                {p.id},
+			}
+			part, err := csw.CreatePart(h)
+			if err != nil {
+				return "", nil, err
+			}
+			if err := writeHTTPFragment(part, p); err != nil {
+				return "", nil, err
+			}
+		}
+		if err := csw.Close(); err != nil {
+			return "", nil, err
+		}
+		part, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type": {fmt.Sprintf("multipart/mixed; boundary=%s", csw.Boundary())},
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		if _, err := part.Write(csBuf.Bytes()); err != nil {
+			return "", nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return "", nil, err
+	}
+	return w.Boundary(), buf.Bytes(), nil
+}
+
+func (b *Batch) executeMultipart(req *resty.Request) error {
+	boundary, body, err := b.encodeMultipart()
+	if err != nil {
+		return err
+	}
+	res, err := req.SetHeader("Content-Type", fmt.Sprintf("multipart/mixed; boundary=%s", boundary)).SetBody(body).Post(b.url)
+	if err != nil {
+		return err
+	}
+	if res.IsError() {
+		return newRequestError(res)
+	}
+	mediaType, params, err := mime.ParseMediaType(res.Header().Get("Content-Type"))
+	if err != nil {
+		return fmt.Errorf("odata: parsing batch response content type: %w", err)
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return fmt.Errorf("odata: unexpected batch response content type %q", mediaType)
+	}
+	return b.demuxMultipart(bytes.NewReader(res.Body()), params["boundary"])
+}
+
+// demuxMultipart walks the top-level parts of a multipart/mixed batch response. Parts are expected in the
+// order they were added: standalone GETs first (matched positionally), then change sets (matched by Content-ID)
+func (b *Batch) demuxMultipart(r io.Reader, boundary string) error {
+	mr := multipart.NewReader(r, boundary)
+	getIdx, changeSetIdx := 0, 0
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		mediaType, params, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if err != nil {
+			return fmt.Errorf("odata: parsing batch part content type: %w", err)
+		}
+		if strings.HasPrefix(mediaType, "multipart/") {
+			if changeSetIdx >= len(b.changeSets) {
+				return fmt.Errorf("odata: batch response contains more change set results than were requested")
+			}
+			if err := b.demuxChangeSet(part, params["boundary"], b.changeSets[changeSetIdx]); err != nil {
+				return err
+			}
+			changeSetIdx++
+			continue
+		}
+		if getIdx >= len(b.gets) {
+			return fmt.Errorf("odata: batch response contains more GET results than were requested")
+		}
+		if err := populateFromHTTPResponse(b.gets[getIdx], part); err != nil {
+			return err
+		}
+		getIdx++
+	}
+	return nil
+}
+
+func (b *Batch) demuxChangeSet(r io.Reader, boundary string, parts []*batchPart) error {
+	byID := make(map[string]*batchPart, len(parts))
+	for _, p := range parts {
+		byID[p.id] = p
+	}
+	mr := multipart.NewReader(r, boundary)
+	i := 0
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		target := byID[part.Header.Get("Content-ID")]
+		if target == nil && i < len(parts) {
+			target = parts[i]
+		}
+		if target == nil {
+			return fmt.Errorf("odata: change set response contains more results than were requested")
+		}
+		if err := populateFromHTTPResponse(target, part); err != nil {
+			return err
+		}
+		i++
+	}
+	return nil
+}
+
+func populateFromHTTPResponse(p *batchPart, r io.Reader) error {
+	res, err := http.ReadResponse(bufio.NewReader(r), nil)
+	if err != nil {
+		return fmt.Errorf("odata: parsing embedded batch response: %w", err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	p.status = res.StatusCode
+	p.respHeader = res.Header
+	p.respBody = body
+	p.responded = true
+	return nil
+}
+
+// rewriteChangeSetRef rewrites a leading "$<oldID>" reference in a change-set operation's URL (the
+// ChangeOp.URL "$<id>" notation documented on ChangeOp) to use newID instead, so an operation that
+// references an earlier operation of the same change set by its multipart Content-ID still resolves
+// once that id is replaced by the batch-global id required by the JSON batch format.
+func rewriteChangeSetRef(target, oldID, newID string) string {
+	prefix := "$" + oldID
+	if !strings.HasPrefix(target, prefix) {
+		return target
+	}
+	rest := target[len(prefix):]
+	if len(rest) > 0 && rest[0] != '/' {
+		return target
+	}
+	return "$" + newID + rest
+}
+
+type jsonBatchRequest struct {
+	ID             string            `json:"id"`
+	AtomicityGroup string            `json:"atomicityGroup,omitempty"`
+	Method         string            `json:"method"`
+	URL            string            `json:"url"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	Body           json.RawMessage   `json:"body,omitempty"`
+	DependsOn      []string          `json:"dependsOn,omitempty"`
+}
+
+type jsonBatchResponse struct {
+	ID      string            `json:"id"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+func (b *Batch) executeJSON(req *resty.Request) error {
+	envelope := struct {
+		Requests []jsonBatchRequest `json:"requests"`
+	}{}
+	byID := make(map[string]*batchPart, len(b.gets))
+	for _, p := range b.gets {
+		envelope.Requests = append(envelope.Requests, jsonBatchRequest{ID: p.id, Method: p.method, URL: p.target, Headers: p.headers})
+		byID[p.id] = p
+	}
+	// p.id is only unique within its own change set (it also doubles as the multipart Content-ID used by
+	// ChangeOp's "$<id>" back-references), so a batch-global id is derived per change set for the flat JSON
+	// "requests" array; atomicityGroup tells the server to apply the change set's requests atomically, the
+	// role the nested multipart boundary plays in the multipart encoding.
+	for csIdx, cs := range b.changeSets {
+		group := fmt.Sprintf("cs%d", csIdx)
+		ids := make(map[string]string, len(cs))
+		for _, p := range cs {
+			ids[p.id] = fmt.Sprintf("%s-%s", group, p.id)
+		}
+		var dependsOn []string
+		for _, p := range cs {
+			id := ids[p.id]
+			target := p.target
+			for oldID, newID := range ids {
+				target = rewriteChangeSetRef(target, oldID, newID)
+			}
+			r := jsonBatchRequest{ID: id, AtomicityGroup: group, Method: p.method, URL: target, Headers: p.headers, DependsOn: dependsOn}
+			if len(p.body) > 0 {
+				r.Body = json.RawMessage(p.body)
+			}
+			envelope.Requests = append(envelope.Requests, r)
+			dependsOn = []string{id}
+			byID[id] = p
+		}
+	}
+	result := struct {
+		Responses []jsonBatchResponse `json:"responses"`
+	}{}
+	res, err := req.SetBody(envelope).SetResult(&result).Post(b.url)
+	if err != nil {
+		return err
+	}
+	if res.IsError() {
+		return newRequestError(res)
+	}
+	for _, r := range result.Responses {
+		p, ok := byID[r.ID]
+		if !ok {
+			continue
+		}
+		p.status = r.Status
+		p.respBody = r.Body
+		p.responded = true
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package odata
+
+import (
+	"context"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// contextualRequestProvider is implemented by RequestProviders that want to thread a context.Context
+// into the resty.Request they build, e.g. to propagate deadlines or tracing information
+type contextualRequestProvider interface {
+	NewRequestWithContext(ctx context.Context) (*resty.Request, error)
+}
+
+// newRequest builds a request for ctx, using NewRequestWithContext when the provider supports it
+// and falling back to NewRequest plus SetContext otherwise
+func newRequest(ctx context.Context, c RequestProvider) (*resty.Request, error) {
+	if cp, ok := c.(contextualRequestProvider); ok {
+		return cp.NewRequestWithContext(ctx)
+	}
+	r, err := c.NewRequest()
+	if err != nil {
+		return nil, err
+	}
+	return r.SetContext(ctx), nil
+}
@@ -0,0 +1,91 @@
+package odata
+
+import "context"
+
+// Create performs a POST on an OData API, creating a new entity
+func (o *Query[V]) Create(body V) (*V, error) {
+	return o.CreateContext(context.Background(), body)
+}
+
+// CreateContext is Create with a caller-provided context, forwarded to resty.Request.SetContext
+func (o *Query[V]) CreateContext(ctx context.Context, body V) (*V, error) {
+	r, err := o.prepare(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := new(V)
+	res, err := r.SetBody(body).SetResult(result).Post(o.url)
+	if err != nil {
+		return nil, err
+	}
+	if res.IsError() {
+		return nil, newRequestError(res)
+	}
+	return result, nil
+}
+
+// Update performs a PUT on an OData API, replacing an entity in full
+func (o *Query[V]) Update(body V) (*V, error) {
+	return o.UpdateContext(context.Background(), body)
+}
+
+// UpdateContext is Update with a caller-provided context, forwarded to resty.Request.SetContext
+func (o *Query[V]) UpdateContext(ctx context.Context, body V) (*V, error) {
+	r, err := o.prepare(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := new(V)
+	res, err := r.SetBody(body).SetResult(result).Put(o.url)
+	if err != nil {
+		return nil, err
+	}
+	if res.IsError() {
+		return nil, newRequestError(res)
+	}
+	return result, nil
+}
+
+// Patch performs a PATCH on an OData API, merging the provided fields into an existing entity.
+// Use IfMatch to guard the update with an ETag.
+func (o *Query[V]) Patch(body any) (*V, error) {
+	return o.PatchContext(context.Background(), body)
+}
+
+// PatchContext is Patch with a caller-provided context, forwarded to resty.Request.SetContext
+func (o *Query[V]) PatchContext(ctx context.Context, body any) (*V, error) {
+	r, err := o.prepare(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := new(V)
+	res, err := r.SetBody(body).SetResult(result).Patch(o.url)
+	if err != nil {
+		return nil, err
+	}
+	if res.IsError() {
+		return nil, newRequestError(res)
+	}
+	return result, nil
+}
+
+// Delete performs a DELETE on an OData API, removing an entity
+func (o *Query[V]) Delete() error {
+	return o.DeleteContext(context.Background())
+}
+
+// DeleteContext is Delete with a caller-provided context, forwarded to resty.Request.SetContext
+func (o *Query[V]) DeleteContext(ctx context.Context) error {
+	r, err := o.prepare(ctx)
+	if err != nil {
+		return err
+	}
+	res, err := r.Delete(o.url)
+	if err != nil {
+		return err
+	}
+	if res.IsError() {
+		return newRequestError(res)
+	}
+	return nil
+}
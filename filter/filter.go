@@ -0,0 +1,160 @@
+// Package filter provides a type-safe builder for OData 4.0 $filter expressions,
+// so callers don't have to hand-format filter strings themselves.
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Expr is an OData filter expression that renders itself to valid OData 4.0 syntax
+type Expr interface {
+	String() string
+}
+
+// GUID wraps a UUID string so it is rendered as a bare OData 4.0 guid literal instead of a quoted string
+type GUID string
+
+// value renders v as an OData literal
+func value(v any) string {
+	switch val := v.(type) {
+	case string:
+		return "'" + strings.ReplaceAll(val, "'", "''") + "'"
+	case GUID:
+		return string(val)
+	case time.Time:
+		return val.UTC().Format(time.RFC3339)
+	case bool:
+		if val {
+			return "true"
+		}
+		return "false"
+	case nil:
+		return "null"
+	case fmt.Stringer:
+		return val.String()
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
+// fieldString renders a comparison operand that is either a bare field name or a nested Expr such as ToLower
+func fieldString(f any) string {
+	switch v := f.(type) {
+	case string:
+		return v
+	case Expr:
+		return v.String()
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+type comparison struct {
+	field any
+	op    string
+	value any
+}
+
+func (c comparison) String() string {
+	return fmt.Sprintf("%s %s %s", fieldString(c.field), c.op, value(c.value))
+}
+
+// Eq builds a "field eq value" comparison
+func Eq(field any, value any) Expr { return comparison{field, "eq", value} }
+
+// Ne builds a "field ne value" comparison
+func Ne(field any, value any) Expr { return comparison{field, "ne", value} }
+
+// Gt builds a "field gt value" comparison
+func Gt(field any, value any) Expr { return comparison{field, "gt", value} }
+
+// Ge builds a "field ge value" comparison
+func Ge(field any, value any) Expr { return comparison{field, "ge", value} }
+
+// Lt builds a "field lt value" comparison
+func Lt(field any, value any) Expr { return comparison{field, "lt", value} }
+
+// Le builds a "field le value" comparison
+func Le(field any, value any) Expr { return comparison{field, "le", value} }
+
+type logical struct {
+	op    string
+	exprs []Expr
+}
+
+func (l logical) String() string {
+	parts := make([]string, len(l.exprs))
+	for i, e := range l.exprs {
+		parts[i] = "(" + e.String() + ")"
+	}
+	return strings.Join(parts, " "+l.op+" ")
+}
+
+// And combines expressions with the OData "and" operator
+func And(exprs ...Expr) Expr { return logical{"and", exprs} }
+
+// Or combines expressions with the OData "or" operator
+func Or(exprs ...Expr) Expr { return logical{"or", exprs} }
+
+type not struct {
+	expr Expr
+}
+
+func (n not) String() string {
+	return "not (" + n.expr.String() + ")"
+}
+
+// Not negates an expression
+func Not(expr Expr) Expr { return not{expr} }
+
+type call struct {
+	name string
+	args []string
+}
+
+func (c call) String() string {
+	return c.name + "(" + strings.Join(c.args, ",") + ")"
+}
+
+// Contains builds a "contains(field, value)" string function call
+func Contains(field any, substr string) Expr {
+	return call{"contains", []string{fieldString(field), value(substr)}}
+}
+
+// StartsWith builds a "startswith(field, value)" string function call
+func StartsWith(field any, prefix string) Expr {
+	return call{"startswith", []string{fieldString(field), value(prefix)}}
+}
+
+// EndsWith builds an "endswith(field, value)" string function call
+func EndsWith(field any, suffix string) Expr {
+	return call{"endswith", []string{fieldString(field), value(suffix)}}
+}
+
+// ToLower builds a "tolower(field)" string function, usable as the field operand of a comparison
+func ToLower(field any) Expr {
+	return call{"tolower", []string{fieldString(field)}}
+}
+
+type lambda struct {
+	collection string
+	op         string
+	varName    string
+	pred       Expr
+}
+
+func (l lambda) String() string {
+	return fmt.Sprintf("%s/%s(%s:%s)", l.collection, l.op, l.varName, l.pred.String())
+}
+
+// Any builds a "collection/any(varName:pred)" lambda expression
+func Any(collection, varName string, pred Expr) Expr {
+	return lambda{collection, "any", varName, pred}
+}
+
+// All builds a "collection/all(varName:pred)" lambda expression
+func All(collection, varName string, pred Expr) Expr {
+	return lambda{collection, "all", varName, pred}
+}
@@ -0,0 +1,105 @@
+package odata
+
+import (
+	"context"
+	"iter"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// fetchPage performs a single page request using req and unmarshals the result as a page of V
+func fetchPage[V any](req *resty.Request, url string) (*Respose[V], error) {
+	result := new(Respose[V])
+	res, err := req.SetResult(result).Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if res.IsError() {
+		return nil, newRequestError(res)
+	}
+	return result, nil
+}
+
+// Iterator is a forward-only, non-buffering iterator over the pages of an OData result set
+type Iterator[V any] struct {
+	query  *Query[V]
+	next   string
+	first  bool
+	items  []V
+	idx    int
+	cur    V
+	err    error
+	closed bool
+}
+
+// Iter returns an Iterator that fetches one page at a time, following @odata.nextLink, instead of collecting all pages upfront
+func (o *Query[V]) Iter() *Iterator[V] {
+	return &Iterator[V]{query: o, next: o.url, first: true}
+}
+
+// Next advances the iterator, fetching another page if the current one is exhausted. It returns false at the end of the result set or on error
+func (it *Iterator[V]) Next(ctx context.Context) bool {
+	if it.closed || it.err != nil {
+		return false
+	}
+	for it.idx >= len(it.items) {
+		if !it.first && len(it.next) == 0 {
+			return false
+		}
+		var req *resty.Request
+		var err error
+		if it.first {
+			req, err = it.query.prepare(ctx)
+		} else {
+			req, err = newRequest(ctx, it.query.client)
+		}
+		if err != nil {
+			it.err = err
+			return false
+		}
+		page, err := fetchPage[V](req, it.next)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.items = page.Value
+		it.idx = 0
+		it.next = page.Next
+		it.first = false
+	}
+	it.cur = it.items[it.idx]
+	it.idx++
+	return true
+}
+
+// Value returns the item the iterator currently points to, valid after a call to Next returned true
+func (it *Iterator[V]) Value() V {
+	return it.cur
+}
+
+// Err returns the error that stopped iteration, if any
+func (it *Iterator[V]) Err() error {
+	return it.err
+}
+
+// Close stops the iterator. It is safe to call multiple times
+func (it *Iterator[V]) Close() error {
+	it.closed = true
+	return nil
+}
+
+// All returns an iter.Seq2 adapter over the result set, so callers can write "for item, err := range query.All()"
+func (o *Query[V]) All() iter.Seq2[V, error] {
+	return func(yield func(V, error) bool) {
+		it := o.Iter()
+		defer it.Close()
+		for it.Next(context.Background()) {
+			if !yield(it.Value(), nil) {
+				return
+			}
+		}
+		if err := it.Err(); err != nil {
+			yield(*new(V), err)
+		}
+	}
+}
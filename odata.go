@@ -1,32 +1,77 @@
 package odata
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 
+	"github.com/FossoresLP/odata/apply"
+	"github.com/FossoresLP/odata/filter"
 	"github.com/go-resty/resty/v2"
 )
 
+// ODataErrorDetail is a single entry of the "details" array in an OData error envelope
+type ODataErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Target  string `json:"target,omitempty"`
+}
+
+// ODataError is the "error" object of an OData error envelope as defined by the OData JSON format spec
+type ODataError struct {
+	Code    string             `json:"code"`
+	Message string             `json:"message"`
+	Details []ODataErrorDetail `json:"details,omitempty"`
+}
+
 type RequestError struct {
 	Status     int
 	StatusText string
 	Body       string
+	// OData is populated when the response body could be parsed as an OData error envelope
+	OData *ODataError
 }
 
 func (r *RequestError) Error() string {
+	if r.OData != nil {
+		return fmt.Sprintf("request failed with status %d - %s: %s (%s)", r.Status, r.StatusText, r.OData.Message, r.OData.Code)
+	}
 	return fmt.Sprintf("request failed with status %d - %s: %q", r.Status, r.StatusText, r.Body)
 }
 
-// RequestProviders generate Resty requests that already contain a base URL and the necessary authentication information for the OData API
+// newRequestError builds a RequestError from a failed response, additionally parsing the body as an OData error envelope if possible
+func newRequestError(res *resty.Response) *RequestError {
+	return newRequestErrorFromBody(res.StatusCode(), res.Status(), res.Body())
+}
+
+// newRequestErrorFromBody is the resty-independent core of newRequestError, used to build errors from sub-responses
+// that were demultiplexed out of a $batch response rather than received directly from resty
+func newRequestErrorFromBody(status int, statusText string, body []byte) *RequestError {
+	err := &RequestError{Status: status, StatusText: statusText, Body: string(body)}
+	var envelope struct {
+		Error ODataError `json:"error"`
+	}
+	if e := json.Unmarshal(body, &envelope); e == nil && envelope.Error.Code != "" {
+		err.OData = &envelope.Error
+	}
+	return err
+}
+
+// RequestProviders generate Resty requests that already contain a base URL and the necessary authentication information for the OData API.
+// A provider may additionally implement NewRequestWithContext(context.Context) (*resty.Request, error) to thread a context
+// into the request it builds; this is detected via type assertion and is not part of the interface to preserve backward compatibility.
 type RequestProvider interface {
 	NewRequest() (*resty.Request, error)
 }
 
 type Respose[V any] struct {
-	Context string `json:"@odata.context"`
-	Count   uint64 `json:"@odata.count"`
-	Next    string `json:"@odata.nextLink"`
-	Value   []V    `json:"value"`
+	Context   string `json:"@odata.context"`
+	Count     uint64 `json:"@odata.count"`
+	Next      string `json:"@odata.nextLink"`
+	DeltaLink string `json:"@odata.deltaLink"`
+	Value     []V    `json:"value"`
 }
 
 // Result returns the data contained in the OData response
@@ -36,21 +81,22 @@ func (o *Respose[V]) Result() []V {
 
 // Collect iterates through pages of OData results and collects them into the original result
 func (o *Respose[V]) Collect(c RequestProvider) error {
+	return o.CollectContext(context.Background(), c)
+}
+
+// CollectContext is Collect with a caller-provided context, forwarded to every page request
+func (o *Respose[V]) CollectContext(ctx context.Context, c RequestProvider) error {
 	for last := o; len(last.Next) > 0; {
-		req, err := c.NewRequest()
+		req, err := newRequest(ctx, c)
 		if err != nil {
 			return err
 		}
-		result := Respose[V]{}
-		res, err := req.SetResult(&result).Get(last.Next)
+		result, err := fetchPage[V](req, last.Next)
 		if err != nil {
 			return err
 		}
-		if res.IsError() {
-			return &RequestError{res.StatusCode(), res.Status(), res.String()}
-		}
 		o.Value = append(o.Value, result.Result()...)
-		last = &result
+		last = result
 	}
 	return nil
 }
@@ -94,6 +140,8 @@ type Query[V any] struct {
 	skip       uint64
 	top        uint64
 	pathParams map[string]string
+	etag       string
+	apply      string
 }
 
 // NewQuery creates a new OData query for a specific URL that will be resolved with the provided RequestProvider
@@ -124,6 +172,20 @@ func (o *Query[V]) Filter(filter string) *Query[V] {
 	return o
 }
 
+// FilterExpr sets a filter expression built with the filter subpackage, as an alternative to the raw string accepted by Filter
+func (o *Query[V]) FilterExpr(expr filter.Expr) *Query[V] {
+	o.filter = expr.String()
+	return o
+}
+
+// Apply sets a $apply pipeline built with the apply subpackage, for server-side aggregation. Since aggregation
+// results generally have a different shape than V, use the package-level ApplyAs to re-parameterize the
+// result type instead if the pipeline changes the shape of the result.
+func (o *Query[V]) Apply(p apply.Pipeline) *Query[V] {
+	o.apply = p.String()
+	return o
+}
+
 // OrderBy defines the key and direction to order the results by
 func (o *Query[V]) OrderBy(key string, direction ...Direction) *Query[V] {
 	if len(direction) > 0 {
@@ -164,12 +226,22 @@ func (o *Query[V]) PathParam(key, value string) *Query[V] {
 	return o
 }
 
+// IfMatch sets the ETag to send as an If-Match header on every request built from this query, typically
+// with Patch to guard against lost updates
+func (o *Query[V]) IfMatch(etag string) *Query[V] {
+	o.etag = etag
+	return o
+}
+
 // Prepare creates a new OData request using the RequestProvider and sets the queries according to the builder functions
-func (o *Query[V]) prepare() (*resty.Request, error) {
-	r, err := o.client.NewRequest()
+func (o *Query[V]) prepare(ctx context.Context) (*resty.Request, error) {
+	r, err := newRequest(ctx, o.client)
 	if err != nil {
 		return nil, err
 	}
+	if len(o.etag) > 0 {
+		r.SetHeader("If-Match", o.etag)
+	}
 	if o.count {
 		r.SetQueryParam("$count", "true")
 	}
@@ -188,12 +260,26 @@ func (o *Query[V]) prepare() (*resty.Request, error) {
 	if len(o.selectKeys) > 0 {
 		r.SetQueryParam("$select", strings.Join(o.selectKeys, ","))
 	}
+	if len(o.apply) > 0 {
+		r.SetQueryParam("$apply", o.apply)
+	}
+	if o.skip > 0 {
+		r.SetQueryParam("$skip", strconv.FormatUint(o.skip, 10))
+	}
+	if o.top > 0 {
+		r.SetQueryParam("$top", strconv.FormatUint(o.top, 10))
+	}
 	return r.SetPathParams(o.pathParams), nil
 }
 
 // Get performs a simple get on an OData API returning a single item
 func (o *Query[V]) Get() (*V, error) {
-	r, err := o.prepare()
+	return o.GetContext(context.Background())
+}
+
+// GetContext is Get with a caller-provided context, forwarded to resty.Request.SetContext
+func (o *Query[V]) GetContext(ctx context.Context) (*V, error) {
+	r, err := o.prepare(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -203,27 +289,27 @@ func (o *Query[V]) Get() (*V, error) {
 		return nil, err
 	}
 	if res.IsError() {
-		return nil, &RequestError{res.StatusCode(), res.Status(), res.String()}
+		return nil, newRequestError(res)
 	}
 	return result, nil
 }
 
 // GetAll performs an OData request for a set of items, iterating through all pages and collecting the results
 func (o *Query[V]) GetAll() ([]V, error) {
-	r, err := o.prepare()
+	return o.GetAllContext(context.Background())
+}
+
+// GetAllContext is GetAll with a caller-provided context, forwarded to every page request
+func (o *Query[V]) GetAllContext(ctx context.Context) ([]V, error) {
+	r, err := o.prepare(ctx)
 	if err != nil {
 		return nil, err
 	}
-	result := Respose[V]{}
-	res, err := r.SetResult(&result).Get(o.url)
+	result, err := fetchPage[V](r, o.url)
 	if err != nil {
 		return nil, err
 	}
-	if res.IsError() {
-		return nil, &RequestError{res.StatusCode(), res.Status(), res.String()}
-	}
-	err = result.Collect(o.client)
-	if err != nil {
+	if err := result.CollectContext(ctx, o.client); err != nil {
 		return nil, err
 	}
 	return result.Value, nil
@@ -0,0 +1,123 @@
+package odata
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// odataFieldName returns the OData property name for a struct field, honoring an "odata" tag (falling back
+// to the Go field name), with "-" opting the field out entirely
+func odataFieldName(f reflect.StructField) (name string, skip bool) {
+	tag, ok := f.Tag.Lookup("odata")
+	if ok && tag == "-" {
+		return "", true
+	}
+	if !f.IsExported() {
+		return "", true
+	}
+	if ok && len(tag) > 0 {
+		return tag, false
+	}
+	return f.Name, false
+}
+
+// buildSelectExpand walks t's fields, collecting the OData property names of scalar fields directly and
+// turning struct (or slice-of-struct) navigation properties into "$expand=Name($select=...;$expand=...)" clauses.
+// visited tracks the types already on the current recursion path so self-referential navigation properties
+// (e.g. Employee.Manager *Employee) stop at the repeat instead of recursing forever.
+func buildSelectExpand(t reflect.Type, visited map[reflect.Type]bool) (selects []string, expands []string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	visited[t] = true
+	defer delete(visited, t)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, skip := odataFieldName(f)
+		if skip {
+			continue
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Slice {
+			ft = ft.Elem()
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+		}
+		if ft.Kind() == reflect.Struct && ft != timeType && !visited[ft] {
+			childSelects, childExpands := buildSelectExpand(ft, visited)
+			expands = append(expands, expandClause(name, childSelects, childExpands))
+			continue
+		}
+		selects = append(selects, name)
+	}
+	return selects, expands
+}
+
+func expandClause(name string, selects, expands []string) string {
+	var clauses []string
+	if len(selects) > 0 {
+		clauses = append(clauses, "$select="+strings.Join(selects, ","))
+	}
+	if len(expands) > 0 {
+		clauses = append(clauses, "$expand="+strings.Join(expands, ","))
+	}
+	if len(clauses) == 0 {
+		return name
+	}
+	return name + "(" + strings.Join(clauses, ";") + ")"
+}
+
+// SelectAuto derives $select and $expand from V's struct tags, following nested struct (or slice-of-struct)
+// fields into a properly nested "$expand=Parent($select=Child)" tree, so callers don't have to restate field
+// names as strings. Fields are named by their "odata" tag, falling back to the Go field name; a tag of "-"
+// excludes the field.
+func (o *Query[V]) SelectAuto() *Query[V] {
+	selects, expands := buildSelectExpand(reflect.TypeFor[V](), map[reflect.Type]bool{})
+	o.selectKeys = selects
+	o.expand = expands
+	return o
+}
+
+// OrderByTag orders by the field of V tagged odata:"tag" (or named tag if untagged), validated against V's
+// struct so a typo fails when the query is built rather than when the server rejects it. tag not matching
+// any field of V is a caller error (e.g. a typo), not a condition the query builder should crash on, so it
+// is returned as an error rather than a panic.
+func (o *Query[V]) OrderByTag(tag string, direction ...Direction) (*Query[V], error) {
+	t := reflect.TypeFor[V]()
+	for i := 0; i < t.NumField(); i++ {
+		name, skip := odataFieldName(t.Field(i))
+		if !skip && name == tag {
+			return o.OrderBy(name, direction...), nil
+		}
+	}
+	return nil, fmt.Errorf("odata: OrderByTag: %s has no field tagged %q", t, tag)
+}
+
+// OrderByField orders by the field of base that field points into, e.g. OrderByField(q, &entity, &entity.Name).
+// base and field must point into the same V value; the field is located by matching memory offsets, so typos
+// are caught by the compiler instead of surfacing as a server-side error.
+func OrderByField[V, F any](o *Query[V], base *V, field *F, direction ...Direction) *Query[V] {
+	offset := uintptr(unsafe.Pointer(field)) - uintptr(unsafe.Pointer(base))
+	t := reflect.TypeFor[V]()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Offset != offset {
+			continue
+		}
+		name, skip := odataFieldName(f)
+		if skip {
+			break
+		}
+		return o.OrderBy(name, direction...)
+	}
+	panic(fmt.Sprintf("odata: OrderByField: field pointer does not reference a field of %s", t))
+}